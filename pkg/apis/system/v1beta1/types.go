@@ -0,0 +1,66 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureGate describes the runtime state of a single Antrea feature gate, as observed by the
+// Antrea component (Agent or Controller) serving this object. Its ObjectMeta.Name is the
+// feature's name, e.g. "AntreaPolicy".
+type FeatureGate struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	// Component is the Antrea component reporting this feature gate, e.g. "agent", "agent-windows"
+	// or "controller".
+	Component string `json:"component" protobuf:"bytes,2,opt,name=component"`
+	// Status is either "Enabled" or "Disabled", reflecting whether the feature is currently
+	// switched on for Component.
+	Status string `json:"status" protobuf:"bytes,3,opt,name=status"`
+	// Version is the feature's maturity level (ALPHA, BETA or left empty once it is GA),
+	// mirroring component-base/featuregate.PreRelease.
+	// +optional
+	Version string `json:"version,omitempty" protobuf:"bytes,4,opt,name=version"`
+	// EffectiveStatus is what the code will actually do once feature gate dependencies and
+	// conflicts are resolved. It is only set when it differs from Status.
+	// +optional
+	EffectiveStatus string `json:"effectiveStatus,omitempty" protobuf:"bytes,5,opt,name=effectiveStatus"`
+	// Reason explains why EffectiveStatus differs from Status.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,6,opt,name=reason"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FeatureGateList is a list of FeatureGate objects.
+type FeatureGateList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty" protobuf:"bytes,1,opt,name=metadata"`
+
+	Items []FeatureGate `json:"items" protobuf:"bytes,2,rep,name=items"`
+
+	// Warnings lists feature-gate dependency or conflict violations found while resolving the
+	// requested feature gate states, e.g. a feature enabled without a required prerequisite.
+	// Operators should resolve these through configuration rather than discovering them at
+	// datapath init time.
+	// +optional
+	Warnings []string `json:"warnings,omitempty" protobuf:"bytes,3,rep,name=warnings"`
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apiserver ties the resources under pkg/apiserver/storage into the Agent/Controller
+// aggregated API server, so that they are published at their group/version discovery roots and
+// served through genericapiserver's REST and content-negotiation machinery (JSON, YAML and
+// protobuf) rather than the handwritten handlers under pkg/apiserver/handlers.
+package apiserver
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/kubernetes"
+
+	systeminstall "antrea.io/antrea/pkg/apis/system/install"
+	systemv1beta1 "antrea.io/antrea/pkg/apis/system/v1beta1"
+	featuregatestorage "antrea.io/antrea/pkg/apiserver/storage/featuregates"
+)
+
+var (
+	// Scheme is the runtime.Scheme shared by every API group the Agent/Controller aggregated
+	// API server installs.
+	Scheme = runtime.NewScheme()
+	// Codecs provides methods for retrieving codecs and serializers for Scheme.
+	Codecs = serializer.NewCodecFactory(Scheme)
+)
+
+func init() {
+	systeminstall.Install(Scheme)
+}
+
+// InstallSystemAPIGroup registers the system.antrea.io/v1beta1 API group into s, publishing its
+// APIGroup/APIResourceList at the group/version discovery roots and serving the "featuregates"
+// resource, so that "kubectl get antreafeaturegates" works against the Agent or Controller
+// aggregated API server (the resource publishes "antreafeaturegates" as a short name). It is called once
+// from each component's apiserver startup, after the generic apiserver's GenericAPIServer is built
+// and before it starts serving.
+func InstallSystemAPIGroup(s *genericapiserver.GenericAPIServer, k8sClient kubernetes.Interface) error {
+	apiGroupInfo := genericapiserver.NewDefaultAPIGroupInfo(systemv1beta1.GroupName, Scheme, runtime.NewParameterCodec(Scheme), Codecs)
+	apiGroupInfo.VersionedResourcesStorageMap[systemv1beta1.SchemeGroupVersion.Version] = map[string]rest.Storage{
+		"featuregates": featuregatestorage.NewREST(k8sClient),
+	}
+	return s.InstallAPIGroup(&apiGroupInfo)
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregates
+
+import (
+	"fmt"
+
+	"k8s.io/component-base/featuregate"
+
+	"antrea.io/antrea/pkg/features"
+)
+
+func componentForMode(mode Mode) features.Component {
+	switch mode {
+	case ControllerMode:
+		return features.Controller
+	case AgentWindowsMode:
+		return features.Windows
+	default:
+		return features.Agent
+	}
+}
+
+func dependencyAppliesToMode(dep features.Dependency, mode Mode) bool {
+	if len(dep.RequiresMode) == 0 {
+		return true
+	}
+	component := componentForMode(mode)
+	for _, c := range dep.RequiresMode {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDependencies resolves features.FeatureDependencies over requested, the per-feature
+// states cfg asked for, returning the effective state of every feature in requested together with
+// a human-readable reason for every feature whose effective state differs from what was
+// requested, or whose state had to be auto-enabled to satisfy a dependent feature.
+//
+// A Requires violation is resolved by disabling the dependent feature if the missing prerequisite
+// was explicitly disabled by the operator (explicit intent wins), or by auto-enabling the
+// prerequisite if it was only sitting at its default. A Conflicts violation always disables the
+// feature that declared the conflict.
+//
+// features.FeatureDependencies can chain transitively (A requires B requires C), so a single pass
+// over the map, whose iteration order is randomized, is not enough: whether a multi-hop
+// requirement converges would otherwise depend on which order the map happened to be walked in.
+// resolveDependencies instead repeats the pass to a fixed point, bounded by the number of declared
+// dependencies, which is the most hops a chain without a cycle can have.
+func resolveDependencies(requested, explicit map[featuregate.Feature]bool, mode Mode) (map[featuregate.Feature]bool, map[featuregate.Feature]string) {
+	effective := make(map[featuregate.Feature]bool, len(requested))
+	for f, v := range requested {
+		effective[f] = v
+	}
+	reasons := make(map[featuregate.Feature]string)
+
+	for i := 0; i <= len(features.FeatureDependencies); i++ {
+		changed := false
+		for f, dep := range features.FeatureDependencies {
+			if !effective[f] {
+				continue
+			}
+			if _, known := requested[f]; !known {
+				continue
+			}
+			if !dependencyAppliesToMode(dep, mode) {
+				continue
+			}
+
+			for _, req := range dep.Requires {
+				if _, known := requested[req]; !known {
+					continue
+				}
+				if effective[req] {
+					continue
+				}
+				if explicit[req] {
+					effective[f] = false
+					reasons[f] = fmt.Sprintf("disabled because it requires %s, which is explicitly disabled", req)
+				} else {
+					effective[req] = true
+					reasons[req] = fmt.Sprintf("auto-enabled because %s requires it", f)
+				}
+				changed = true
+			}
+
+			for _, conflict := range dep.Conflicts {
+				if _, known := requested[conflict]; !known {
+					continue
+				}
+				if effective[conflict] {
+					effective[f] = false
+					reasons[f] = fmt.Sprintf("disabled due to conflict with enabled feature %s", conflict)
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return effective, reasons
+}
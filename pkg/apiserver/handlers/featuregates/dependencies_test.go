@@ -0,0 +1,123 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/component-base/featuregate"
+
+	"antrea.io/antrea/pkg/features"
+)
+
+func TestResolveDependencies_RequiresAutoEnable(t *testing.T) {
+	requested := map[featuregate.Feature]bool{
+		features.L7NetworkPolicy: true,
+		features.AntreaPolicy:    false,
+	}
+	explicit := map[featuregate.Feature]bool{
+		features.L7NetworkPolicy: true,
+	}
+
+	effective, reasons := resolveDependencies(requested, explicit, AgentMode)
+
+	assert.True(t, effective[features.L7NetworkPolicy], "L7NetworkPolicy should stay enabled")
+	assert.True(t, effective[features.AntreaPolicy], "AntreaPolicy should be auto-enabled to satisfy L7NetworkPolicy")
+	assert.Contains(t, reasons[features.AntreaPolicy], "auto-enabled")
+}
+
+func TestResolveDependencies_RequiresExplicitDisableWins(t *testing.T) {
+	requested := map[featuregate.Feature]bool{
+		features.L7NetworkPolicy: true,
+		features.AntreaPolicy:    false,
+	}
+	explicit := map[featuregate.Feature]bool{
+		features.L7NetworkPolicy: true,
+		features.AntreaPolicy:    true,
+	}
+
+	effective, reasons := resolveDependencies(requested, explicit, AgentMode)
+
+	assert.False(t, effective[features.L7NetworkPolicy], "L7NetworkPolicy should be disabled since its prerequisite was explicitly disabled")
+	assert.Contains(t, reasons[features.L7NetworkPolicy], "explicitly disabled")
+}
+
+func TestResolveDependencies_Conflicts(t *testing.T) {
+	requested := map[featuregate.Feature]bool{
+		features.Multicluster: true,
+		features.Egress:       true,
+	}
+	explicit := map[featuregate.Feature]bool{
+		features.Multicluster: true,
+		features.Egress:       true,
+	}
+
+	effective, reasons := resolveDependencies(requested, explicit, AgentMode)
+
+	assert.False(t, effective[features.Multicluster], "Multicluster should be disabled due to conflict with Egress")
+	assert.True(t, effective[features.Egress], "Egress is not the side declaring the conflict, so it stays enabled")
+	assert.Contains(t, reasons[features.Multicluster], "conflict with enabled feature Egress")
+}
+
+func TestResolveDependencies_RequiresMode(t *testing.T) {
+	requested := map[featuregate.Feature]bool{
+		features.Multicluster: true,
+		features.Egress:       true,
+	}
+	explicit := map[featuregate.Feature]bool{
+		features.Multicluster: true,
+		features.Egress:       true,
+	}
+
+	effective, reasons := resolveDependencies(requested, explicit, ControllerMode)
+
+	assert.True(t, effective[features.Multicluster], "Multicluster's Conflicts with Egress only applies to Agent/Windows, not Controller")
+	assert.Empty(t, reasons[features.Multicluster])
+
+	for _, mode := range []Mode{AgentMode, AgentWindowsMode} {
+		effective, reasons = resolveDependencies(requested, explicit, mode)
+		assert.False(t, effective[features.Multicluster], "Multicluster's Conflicts with Egress applies to mode %s", mode)
+		assert.Contains(t, reasons[features.Multicluster], "conflict with enabled feature Egress")
+	}
+}
+
+// TestResolveDependencies_TransitiveChain guards against resolveDependencies regressing to a
+// single pass over features.FeatureDependencies, whose map iteration order is randomized: a
+// single pass would only auto-enable a multi-hop chain's prerequisites when the map happened to
+// be walked from the start of the chain forwards. It swaps in a synthetic A->B->C chain (reusing
+// real feature constants as stand-ins, since resolveDependencies only cares about the keys) so the
+// test doesn't depend on features.FeatureDependencies staying single-hop.
+func TestResolveDependencies_TransitiveChain(t *testing.T) {
+	a, b, c := features.L7NetworkPolicy, features.LoadBalancerModeDSR, features.Multicluster
+
+	original := features.FeatureDependencies
+	features.FeatureDependencies = map[featuregate.Feature]features.Dependency{
+		a: {Requires: []featuregate.Feature{b}},
+		b: {Requires: []featuregate.Feature{c}},
+	}
+	defer func() { features.FeatureDependencies = original }()
+
+	requested := map[featuregate.Feature]bool{a: true, b: false, c: false}
+	explicit := map[featuregate.Feature]bool{a: true}
+
+	effective, reasons := resolveDependencies(requested, explicit, AgentMode)
+
+	assert.True(t, effective[a], "a should stay enabled")
+	assert.True(t, effective[b], "b should be auto-enabled to satisfy a, even though resolving it only enables c in the same pass")
+	assert.True(t, effective[c], "c should be auto-enabled transitively to satisfy b")
+	assert.Contains(t, reasons[b], "auto-enabled")
+	assert.Contains(t, reasons[c], "auto-enabled")
+}
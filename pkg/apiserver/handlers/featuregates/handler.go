@@ -0,0 +1,496 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featuregates implements the handler and backing registry for Antrea's feature gate
+// introspection. It is consumed in two ways: the legacy "/featuregates" HTTP endpoint installed
+// directly on the Agent/Controller API server mux (HandleFunc, kept for backwards compatibility),
+// and the "featuregates" resource of the aggregated system.antrea.io/v1beta1 API group
+// implemented by pkg/apiserver/storage/featuregates on top of GetFeatureGatesResponse.
+package featuregates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"antrea.io/antrea/pkg/features"
+	antrearuntime "antrea.io/antrea/pkg/util/runtime"
+)
+
+const (
+	podNameEnvKey       = "POD_NAME"
+	podNamespaceEnvKey  = "POD_NAMESPACE"
+	configMapNameEnvKey = "ANTREA_CONFIG_MAP_NAME"
+
+	agentConfigMapKey      = "antrea-agent.conf"
+	controllerConfigMapKey = "antrea-controller.conf"
+)
+
+// Mode identifies which Antrea component a set of feature gates is being reported for.
+type Mode string
+
+const (
+	AgentMode        Mode = "agent"
+	ControllerMode   Mode = "controller"
+	AgentWindowsMode Mode = "agent-windows"
+)
+
+// Config is the subset of the Agent/Controller configuration that is relevant to feature gates.
+type Config struct {
+	FeatureGates map[string]bool `yaml:"featureGates,omitempty"`
+}
+
+// Response is a single feature gate's status as reported for one Antrea component. It is kept
+// stable as the wire format of the legacy "/featuregates" endpoint; the aggregated
+// system.antrea.io/v1beta1 FeatureGate object is converted from it by toFeatureGate in
+// pkg/apiserver/storage/featuregates.
+type Response struct {
+	Component string `json:"component,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Version   string `json:"version,omitempty"`
+	// EffectiveStatus is what the code will actually do once features.FeatureDependencies is
+	// resolved. It is only set when it differs from Status.
+	EffectiveStatus string `json:"effectiveStatus,omitempty"`
+	// Reason explains why EffectiveStatus differs from Status, e.g. because a dependency was
+	// unsatisfied or a conflicting feature gate was enabled.
+	Reason string `json:"reason,omitempty"`
+}
+
+// agentFeatures, controllerFeatures and agentWindowsFeatures list, for each component, the
+// features.DefaultAntreaFeatureGates keys that are applicable to it. A feature omitted from a
+// component's list is simply not reported for that component (e.g. Windows does not support the
+// OVS-only features such as Egress or Multicast).
+var (
+	agentFeatures = []featuregate.Feature{
+		features.AntreaIPAM,
+		features.AntreaPolicy,
+		features.AntreaProxy,
+		features.CleanupStaleUDPSvcConntrack,
+		features.Egress,
+		features.EndpointSlice,
+		features.ExternalNode,
+		features.FlowExporter,
+		features.IPsecCertAuth,
+		features.L7NetworkPolicy,
+		features.LoadBalancerModeDSR,
+		features.Multicast,
+		features.Multicluster,
+		features.NetworkPolicyStats,
+		features.NodePortLocal,
+		features.SecondaryNetwork,
+		features.ServiceExternalIP,
+		features.SupportBundleCollection,
+		features.TopologyAwareHints,
+		features.Traceflow,
+		features.TrafficControl,
+	}
+
+	controllerFeatures = []featuregate.Feature{
+		features.AdminNetworkPolicy,
+		features.AntreaIPAM,
+		features.AntreaPolicy,
+		features.Egress,
+		features.IPsecCertAuth,
+		features.L7NetworkPolicy,
+		features.Multicast,
+		features.Multicluster,
+		features.NetworkPolicyStats,
+		features.NodeIPAM,
+		features.ServiceExternalIP,
+		features.SupportBundleCollection,
+		features.Traceflow,
+	}
+
+	agentWindowsFeatures = []featuregate.Feature{
+		features.AntreaPolicy,
+		features.AntreaProxy,
+		features.EndpointSlice,
+		features.ExternalNode,
+		features.FlowExporter,
+		features.NetworkPolicyStats,
+		features.NodePortLocal,
+		features.SupportBundleCollection,
+		features.TopologyAwareHints,
+		features.Traceflow,
+		features.TrafficControl,
+	}
+)
+
+func featuresForMode(mode Mode) []featuregate.Feature {
+	switch mode {
+	case ControllerMode:
+		return controllerFeatures
+	case AgentWindowsMode:
+		return agentWindowsFeatures
+	default:
+		return agentFeatures
+	}
+}
+
+func getStatus(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+// requestedStates computes, for every feature applicable to mode, the status cfg requests
+// (defaulting to features.DefaultAntreaFeatureGates when cfg leaves it unset) and whether the
+// operator set it explicitly.
+func requestedStates(cfg *Config, mode Mode) (requested, explicit map[featuregate.Feature]bool) {
+	supported := featuresForMode(mode)
+	requested = make(map[featuregate.Feature]bool, len(supported))
+	explicit = make(map[featuregate.Feature]bool, len(supported))
+	for _, f := range supported {
+		enabled := features.DefaultAntreaFeatureGates[f].Default
+		if cfg != nil {
+			if v, ok := cfg.FeatureGates[string(f)]; ok {
+				enabled = v
+				explicit[f] = true
+			}
+		}
+		requested[f] = enabled
+	}
+	return requested, explicit
+}
+
+// getFeatureGatesResponse computes the Response rows for mode, applying any overrides from cfg on
+// top of features.DefaultAntreaFeatureGates, then resolving features.FeatureDependencies to fill
+// in EffectiveStatus/Reason wherever the resolved state differs from the requested one. Rows are
+// returned sorted by feature name.
+func getFeatureGatesResponse(cfg *Config, mode Mode) []Response {
+	supported := featuresForMode(mode)
+	requested, explicit := requestedStates(cfg, mode)
+	effective, reasons := resolveDependencies(requested, explicit, mode)
+
+	responses := make([]Response, 0, len(supported))
+	for _, f := range supported {
+		spec := features.DefaultAntreaFeatureGates[f]
+		resp := Response{
+			Component: string(mode),
+			Name:      string(f),
+			Status:    getStatus(requested[f]),
+		}
+		if spec.PreRelease != featuregate.GA {
+			resp.Version = string(spec.PreRelease)
+		}
+		if effective[f] != requested[f] {
+			resp.EffectiveStatus = getStatus(effective[f])
+		}
+		resp.Reason = reasons[f]
+		responses = append(responses, resp)
+	}
+	sort.Slice(responses, func(i, j int) bool { return responses[i].Name < responses[j].Name })
+	return responses
+}
+
+// getWarnings reports, for mode, every features.FeatureDependencies violation found while
+// resolving cfg's requested feature gate states: an unsatisfied Requires, a dependency that had
+// to be auto-enabled, or an enabled Conflicts pair.
+func getWarnings(cfg *Config, mode Mode) []string {
+	requested, explicit := requestedStates(cfg, mode)
+	_, reasons := resolveDependencies(requested, explicit, mode)
+
+	warnings := make([]string, 0, len(reasons))
+	for f, reason := range reasons {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", f, reason))
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// ResolveMode determines which Mode the running process reports feature gates for, based on the
+// POD_NAME Pod the process runs as (an "antrea-controller" Pod reports ControllerMode, otherwise
+// AgentMode or AgentWindowsMode depending on the host OS).
+func ResolveMode() Mode {
+	if strings.Contains(os.Getenv(podNameEnvKey), "controller") {
+		return ControllerMode
+	}
+	if antrearuntime.IsWindowsPlatform() {
+		return AgentWindowsMode
+	}
+	return AgentMode
+}
+
+func configMapDataKey(mode Mode) string {
+	if mode == ControllerMode {
+		return controllerConfigMapKey
+	}
+	return agentConfigMapKey
+}
+
+func podNamespace() string {
+	namespace := os.Getenv(podNamespaceEnvKey)
+	if namespace == "" {
+		namespace = metav1.NamespaceSystem
+	}
+	return namespace
+}
+
+func getPod(ctx context.Context, k8sClient kubernetes.Interface, namespace string) (*v1.Pod, error) {
+	podName := os.Getenv(podNameEnvKey)
+	pod, err := k8sClient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting Pod %s/%s: %v", namespace, podName, err)
+	}
+	return pod, nil
+}
+
+func getConfigMap(ctx context.Context, k8sClient kubernetes.Interface, namespace string) (*v1.ConfigMap, error) {
+	configMapName := os.Getenv(configMapNameEnvKey)
+	configMap, err := k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ConfigMap %s/%s: %v", namespace, configMapName, err)
+	}
+	return configMap, nil
+}
+
+// ResolveConfig looks up the antrea-config ConfigMap (identified by the POD_NAME Pod's namespace
+// and the ANTREA_CONFIG_MAP_NAME environment variable) and returns the Config embedded in it,
+// together with the owning Pod, which callers use for auditing (e.g. emitting Events).
+func ResolveConfig(ctx context.Context, k8sClient kubernetes.Interface, mode Mode) (*Config, *v1.Pod, error) {
+	namespace := podNamespace()
+	pod, err := getPod(ctx, k8sClient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	configMap, err := getConfigMap(ctx, k8sClient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[configMapDataKey(mode)]), cfg); err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling Antrea config from ConfigMap %s/%s: %v", namespace, configMap.Name, err)
+	}
+	return cfg, pod, nil
+}
+
+// persistConfig writes cfg.FeatureGates back into the antrea-config ConfigMap's entry for mode.
+// The entry holds the Agent/Controller's full YAML configuration (clusterCIDR, ovsBridge,
+// kubeAPIServerOverride, etc.), of which Config only models the featureGates field, so the
+// existing document is decoded generically and only its "featureGates" key is overwritten,
+// instead of re-marshalling the narrow Config struct over the whole document and losing every
+// other setting.
+func persistConfig(ctx context.Context, k8sClient kubernetes.Interface, mode Mode, cfg *Config) error {
+	namespace := podNamespace()
+	configMap, err := getConfigMap(ctx, k8sClient, namespace)
+	if err != nil {
+		return err
+	}
+	key := configMapDataKey(mode)
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[key]), &raw); err != nil {
+		return fmt.Errorf("error unmarshalling Antrea config from ConfigMap %s/%s: %v", namespace, configMap.Name, err)
+	}
+	if raw == nil {
+		// yaml.Unmarshal round-trips through encoding/json, which sets raw to nil rather than
+		// leaving it empty when configMap.Data[key] is empty or absent (e.g. before the owning
+		// component has written its own conf).
+		raw = map[string]interface{}{}
+	}
+	raw["featureGates"] = cfg.FeatureGates
+
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("error marshalling Antrea config: %v", err)
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[key] = string(data)
+	if _, err := k8sClient.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error updating ConfigMap %s/%s: %v", namespace, configMap.Name, err)
+	}
+	return nil
+}
+
+// recordMutationEvent emits a Kubernetes Event on pod so that runtime feature gate toggles are
+// auditable the same way any other Pod-scoped configuration change is.
+func recordMutationEvent(ctx context.Context, k8sClient kubernetes.Interface, pod *v1.Pod, feature string, enabled bool) {
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-featuregate-", pod.Name),
+			Namespace:    pod.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			UID:       pod.UID,
+		},
+		Reason:         "FeatureGateMutated",
+		Message:        fmt.Sprintf("Feature gate %s was set to %s via the featuregates API", feature, getStatus(enabled)),
+		Type:           v1.EventTypeNormal,
+		Source:         v1.EventSource{Component: "antrea-featuregates"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := k8sClient.CoreV1().Events(pod.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		klog.ErrorS(err, "Failed to record feature gate mutation event", "pod", pod.Name, "feature", feature)
+	}
+}
+
+// GetFeatureGatesResponse is the exported entry point used by the system.antrea.io/v1beta1
+// "featuregates" aggregated resource (see pkg/apiserver/storage/featuregates) to obtain the same
+// rows the legacy HandleFunc endpoint reports.
+func GetFeatureGatesResponse(ctx context.Context, k8sClient kubernetes.Interface) ([]Response, error) {
+	mode := ResolveMode()
+	cfg, _, err := ResolveConfig(ctx, k8sClient, mode)
+	if err != nil {
+		return nil, err
+	}
+	return getFeatureGatesResponse(cfg, mode), nil
+}
+
+// GetWarnings is the exported entry point used by the "featuregates" aggregated resource's List
+// call to surface features.FeatureDependencies violations found in the currently requested
+// feature gate states, so operators discover misconfiguration through
+// "kubectl get antreafeaturegate" rather than at datapath init time.
+func GetWarnings(ctx context.Context, k8sClient kubernetes.Interface) ([]string, error) {
+	mode := ResolveMode()
+	cfg, _, err := ResolveConfig(ctx, k8sClient, mode)
+	if err != nil {
+		return nil, err
+	}
+	return getWarnings(cfg, mode), nil
+}
+
+// MutateRequest is the body accepted by the PATCH/PUT method of HandleFunc to toggle a single
+// feature gate at runtime.
+type MutateRequest struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// GatesResponse is the body returned by the GET method of HandleFunc. Warnings surfaces the same
+// features.FeatureDependencies violations as the aggregated "featuregates" resource's
+// FeatureGateList.Warnings, so operators relying on "antctl get featuregates" (which talks to
+// this legacy endpoint) see misconfiguration without having to move to the aggregated API.
+type GatesResponse struct {
+	FeatureGates []Response `json:"featureGates"`
+	Warnings     []string   `json:"warnings,omitempty"`
+}
+
+// HandleFunc returns the legacy "/featuregates" handler. GET is kept as a compatibility shim for
+// clients that have not migrated to the aggregated system.antrea.io/v1beta1 API: it delegates to
+// the same registry (GetFeatureGatesResponse) backing the "featuregates" resource. PATCH/PUT let
+// an authorized client toggle a feature gate for which features.RuntimeMutableFeatures is true.
+func HandleFunc(k8sClient kubernetes.Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGet(w, r, k8sClient)
+		case http.MethodPatch, http.MethodPut:
+			handleMutate(w, r, k8sClient)
+		default:
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func handleGet(w http.ResponseWriter, r *http.Request, k8sClient kubernetes.Interface) {
+	ctx := r.Context()
+	gatesResponse, err := GetFeatureGatesResponse(ctx, k8sClient)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	warnings, err := GetWarnings(ctx, k8sClient)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	resp := GatesResponse{FeatureGates: gatesResponse, Warnings: warnings}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func handleMutate(w http.ResponseWriter, r *http.Request, k8sClient kubernetes.Interface) {
+	var req MutateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	feature := featuregate.Feature(req.Name)
+	if _, known := features.DefaultAntreaFeatureGates[feature]; !known {
+		http.Error(w, fmt.Sprintf("unknown feature gate %q", req.Name), http.StatusBadRequest)
+		return
+	}
+	if !features.RuntimeMutableFeatures[feature] {
+		http.Error(w, fmt.Sprintf("feature gate %q cannot be toggled at runtime", req.Name), http.StatusForbidden)
+		return
+	}
+
+	ctx := r.Context()
+	mode := ResolveMode()
+	cfg, pod, err := ResolveConfig(ctx, k8sClient, mode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !isFeatureApplicable(feature, mode) {
+		http.Error(w, fmt.Sprintf("feature gate %q is not applicable to %s", req.Name, mode), http.StatusBadRequest)
+		return
+	}
+
+	if cfg.FeatureGates == nil {
+		cfg.FeatureGates = map[string]bool{}
+	}
+	cfg.FeatureGates[req.Name] = req.Enabled
+	if err := persistConfig(ctx, k8sClient, mode, cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{req.Name: req.Enabled}); err != nil {
+		http.Error(w, fmt.Sprintf("error applying feature gate %q at runtime: %v", req.Name, err), http.StatusInternalServerError)
+		return
+	}
+	recordMutationEvent(ctx, k8sClient, pod, req.Name, req.Enabled)
+
+	for _, resp := range getFeatureGatesResponse(cfg, mode) {
+		if resp.Name == req.Name {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+}
+
+func isFeatureApplicable(feature featuregate.Feature, mode Mode) bool {
+	for _, f := range featuresForMode(mode) {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
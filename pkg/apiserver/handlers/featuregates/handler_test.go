@@ -167,11 +167,11 @@ func TestHandleFunc(t *testing.T) {
 	handler.ServeHTTP(recorder, req)
 	require.Equal(t, http.StatusOK, recorder.Code)
 
-	var resp []Response
+	var resp GatesResponse
 	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
 	require.Nil(t, err)
 
-	for _, v := range resp {
+	for _, v := range resp.FeatureGates {
 		df, ok := features.DefaultAntreaFeatureGates[featuregate.Feature(v.Name)]
 		require.True(t, ok)
 		assert.Equal(t, v.Status, getStatus(df.Default))
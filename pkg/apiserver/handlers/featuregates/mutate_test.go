@@ -0,0 +1,106 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package featuregates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestHandleFunc_Mutate(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "antrea-agent-abcde",
+				Namespace: "kube-system",
+				UID:       types.UID("pod-uid"),
+			},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "antrea-config-xyz"},
+			Data: map[string]string{
+				"antrea-agent.conf": "clusterCIDR: 10.0.0.0/16\novsBridge: br-int\nfeatureGates:\n  FlowExporter: false\n",
+			},
+		},
+	)
+
+	os.Setenv("POD_NAME", "antrea-agent-abcde")
+	os.Setenv("ANTREA_CONFIG_MAP_NAME", "antrea-config-xyz")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("ANTREA_CONFIG_MAP_NAME")
+
+	handler := HandleFunc(fakeClient)
+
+	t.Run("toggling a runtime-mutable feature persists and preserves unrelated config", func(t *testing.T) {
+		body, err := json.Marshal(MutateRequest{Name: "FlowExporter", Enabled: true})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPatch, "/featuregates", bytes.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var resp Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.Equal(t, "FlowExporter", resp.Name)
+		assert.Equal(t, "Enabled", resp.Status)
+
+		configMap, err := fakeClient.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "antrea-config-xyz", metav1.GetOptions{})
+		require.NoError(t, err)
+		raw := map[string]interface{}{}
+		require.NoError(t, yaml.Unmarshal([]byte(configMap.Data["antrea-agent.conf"]), &raw))
+		assert.Equal(t, "10.0.0.0/16", raw["clusterCIDR"], "unrelated config keys must survive a feature gate mutation")
+		assert.Equal(t, "br-int", raw["ovsBridge"], "unrelated config keys must survive a feature gate mutation")
+		featureGates, ok := raw["featureGates"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, true, featureGates["FlowExporter"])
+
+		events, err := fakeClient.CoreV1().Events("kube-system").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, events.Items, 1)
+		assert.Equal(t, "FeatureGateMutated", events.Items[0].Reason)
+		assert.Equal(t, "antrea-agent-abcde", events.Items[0].InvolvedObject.Name)
+	})
+
+	t.Run("toggling an immutable feature is rejected", func(t *testing.T) {
+		body, err := json.Marshal(MutateRequest{Name: "AntreaProxy", Enabled: false})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPut, "/featuregates", bytes.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("toggling an unknown feature is rejected", func(t *testing.T) {
+		body, err := json.Marshal(MutateRequest{Name: "NotAFeature", Enabled: true})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPatch, "/featuregates", bytes.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package featuregates implements the "featuregates" resource of the aggregated
+// system.antrea.io/v1beta1 API group. It is registered into the Agent/Controller API server's
+// genericapiserver.APIGroupInfo by pkg/apiserver.InstallSystemAPIGroup, so that
+// "kubectl get antreafeaturegate" and
+// "kubectl get antreafeaturegate <name> -o yaml" are served with full content negotiation
+// (JSON, YAML and protobuf) instead of the handwritten JSON produced by the legacy
+// pkg/apiserver/handlers/featuregates.HandleFunc endpoint, which remains mounted separately for
+// backwards compatibility.
+package featuregates
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/client-go/kubernetes"
+
+	system "antrea.io/antrea/pkg/apis/system/v1beta1"
+	"antrea.io/antrea/pkg/apiserver/handlers/featuregates"
+)
+
+var (
+	_ rest.Storage = &REST{}
+	_ rest.Lister  = &REST{}
+	_ rest.Getter  = &REST{}
+	_ rest.Scoper  = &REST{}
+
+	_ rest.ShortNamesProvider = &REST{}
+)
+
+// REST implements the storage for the "featuregates" resource. It has no backing store: every
+// object is computed on the fly from featuregates.GetFeatureGatesResponse, the same registry the
+// legacy "/featuregates" endpoint uses.
+type REST struct {
+	k8sClient kubernetes.Interface
+}
+
+// NewREST returns a REST storage object backing the "featuregates" resource.
+func NewREST(k8sClient kubernetes.Interface) *REST {
+	return &REST{k8sClient: k8sClient}
+}
+
+func (r *REST) New() runtime.Object {
+	return &system.FeatureGate{}
+}
+
+func (r *REST) NewList() runtime.Object {
+	return &system.FeatureGateList{}
+}
+
+func (r *REST) NamespaceScoped() bool {
+	return false
+}
+
+// ShortNames implements rest.ShortNamesProvider, publishing the names under which kubectl resolves
+// this resource as "antreafeaturegates" and "antreafeaturegate".
+func (r *REST) ShortNames() []string {
+	return []string{"antreafeaturegates", "antreafeaturegate"}
+}
+
+func (r *REST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	responses, err := featuregates.GetFeatureGatesResponse(ctx, r.k8sClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if resp.Name == name {
+			fg := toFeatureGate(resp)
+			return &fg, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(system.Resource("featuregates"), name)
+}
+
+func (r *REST) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	responses, err := featuregates.GetFeatureGatesResponse(ctx, r.k8sClient)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := featuregates.GetWarnings(ctx, r.k8sClient)
+	if err != nil {
+		return nil, err
+	}
+	list := &system.FeatureGateList{
+		Items:    make([]system.FeatureGate, 0, len(responses)),
+		Warnings: warnings,
+	}
+	for _, resp := range responses {
+		list.Items = append(list.Items, toFeatureGate(resp))
+	}
+	return list, nil
+}
+
+func toFeatureGate(resp featuregates.Response) system.FeatureGate {
+	return system.FeatureGate{
+		ObjectMeta:      metav1.ObjectMeta{Name: resp.Name},
+		Component:       resp.Component,
+		Status:          resp.Status,
+		Version:         resp.Version,
+		EffectiveStatus: resp.EffectiveStatus,
+		Reason:          resp.Reason,
+	}
+}
+
+var featureGateTableColumns = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string"},
+	{Name: "Component", Type: "string"},
+	{Name: "Status", Type: "string"},
+	{Name: "Version", Type: "string"},
+	{Name: "Effective Status", Type: "string"},
+}
+
+func featureGateTableRow(fg *system.FeatureGate) metav1.TableRow {
+	return metav1.TableRow{
+		Cells:  []interface{}{fg.Name, fg.Component, fg.Status, fg.Version, fg.EffectiveStatus},
+		Object: runtime.RawExtension{Object: fg},
+	}
+}
+
+// ConvertToTable implements rest.TableConvertor so that the default, no "-o" flag form of
+// "kubectl get antreafeaturegates" (and "... antreafeaturegate <name>") renders a NAME/COMPONENT/
+// STATUS/VERSION/EFFECTIVE STATUS table instead of requiring JSON/YAML output.
+func (r *REST) ConvertToTable(ctx context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	table := &metav1.Table{ColumnDefinitions: featureGateTableColumns}
+	switch t := object.(type) {
+	case *system.FeatureGate:
+		table.Rows = append(table.Rows, featureGateTableRow(t))
+	case *system.FeatureGateList:
+		table.ListMeta = t.ListMeta
+		for i := range t.Items {
+			table.Rows = append(table.Rows, featureGateTableRow(&t.Items[i]))
+		}
+	default:
+		return nil, fmt.Errorf("unexpected object type %T for featuregates table conversion", object)
+	}
+	return table, nil
+}
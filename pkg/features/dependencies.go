@@ -0,0 +1,60 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import "k8s.io/component-base/featuregate"
+
+// Component identifies an Antrea component a feature gate Dependency constraint applies to.
+type Component string
+
+const (
+	Agent      Component = "agent"
+	Controller Component = "controller"
+	Windows    Component = "agent-windows"
+)
+
+// Dependency declares the relationship a feature gate has with other feature gates. It
+// complements DefaultAntreaFeatureGates with constraints that getFeatureGatesResponse resolves
+// into an effective status, so misconfiguration (e.g. enabling a feature without a prerequisite
+// it depends on) is surfaced to the operator instead of only failing at datapath init time.
+type Dependency struct {
+	// Requires lists features that must be enabled for this feature to take effect. If a
+	// required feature is disabled only because it sits at its default, it is auto-enabled;
+	// if it was explicitly disabled by the operator, this feature is disabled instead.
+	Requires []featuregate.Feature
+	// Conflicts lists features that must be disabled for this feature to take effect. If a
+	// conflicting feature is enabled, this feature is disabled.
+	Conflicts []featuregate.Feature
+	// RequiresMode restricts resolution of Requires/Conflicts to the listed components. An
+	// empty RequiresMode applies the constraint to every component the feature is reported for.
+	RequiresMode []Component
+}
+
+// FeatureDependencies declares the cross-feature dependency and conflict graph layered on top of
+// DefaultAntreaFeatureGates.
+var FeatureDependencies = map[featuregate.Feature]Dependency{
+	L7NetworkPolicy: {
+		Requires: []featuregate.Feature{AntreaPolicy},
+	},
+	LoadBalancerModeDSR: {
+		Requires: []featuregate.Feature{AntreaProxy},
+	},
+	Multicluster: {
+		// The Multicluster Gateway relies on the same Node routes Egress programs; running
+		// both on one Node produces conflicting routing rules.
+		Conflicts:    []featuregate.Feature{Egress},
+		RequiresMode: []Component{Agent, Windows},
+	},
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import (
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+
+	antrearuntime "antrea.io/antrea/pkg/util/runtime"
+)
+
+const (
+	// AdminNetworkPolicy enables Support for AdminNetworkPolicy and BaselineAdminNetworkPolicy.
+	AdminNetworkPolicy featuregate.Feature = "AdminNetworkPolicy"
+	// AntreaIPAM enables bridging mode and IPAM for secondary network.
+	AntreaIPAM featuregate.Feature = "AntreaIPAM"
+	// AntreaPolicy enables Antrea ClusterNetworkPolicy and Antrea NetworkPolicy.
+	AntreaPolicy featuregate.Feature = "AntreaPolicy"
+	// AntreaProxy enables Antrea proxy which provides ServiceLB for in-cluster Services.
+	AntreaProxy featuregate.Feature = "AntreaProxy"
+	// CleanupStaleUDPSvcConntrack enables cleanup of stale UDP Service conntrack connections.
+	CleanupStaleUDPSvcConntrack featuregate.Feature = "CleanupStaleUDPSvcConntrack"
+	// Egress enables SNATing traffic from Pods to external network with a configured IP pool.
+	Egress featuregate.Feature = "Egress"
+	// EndpointSlice enables consuming EndpointSlice for Kubernetes Service.
+	EndpointSlice featuregate.Feature = "EndpointSlice"
+	// ExternalNode enables Antrea to support ExternalNode and policy enforcement for virtual machines.
+	ExternalNode featuregate.Feature = "ExternalNode"
+	// FlowExporter enables Antrea agent to export flow information as defined in IPFIX.
+	FlowExporter featuregate.Feature = "FlowExporter"
+	// IPsecCertAuth enables certificate-based authentication for IPsec tunnel.
+	IPsecCertAuth featuregate.Feature = "IPsecCertAuth"
+	// L7NetworkPolicy enables L7NetworkPolicy.
+	L7NetworkPolicy featuregate.Feature = "L7NetworkPolicy"
+	// LoadBalancerModeDSR enables a load balancer mode that can return traffic directly to the client.
+	LoadBalancerModeDSR featuregate.Feature = "LoadBalancerModeDSR"
+	// Multicast enables Antrea multicast.
+	Multicast featuregate.Feature = "Multicast"
+	// Multicluster enables Antrea Multi-cluster features.
+	Multicluster featuregate.Feature = "Multicluster"
+	// NetworkPolicyStats enables NetworkPolicy statistics.
+	NetworkPolicyStats featuregate.Feature = "NetworkPolicyStats"
+	// NodeIPAM enables Node IPAM in Antrea Controller.
+	NodeIPAM featuregate.Feature = "NodeIPAM"
+	// NodePortLocal enables NodePortLocal feature to make Pods reachable via NodePort on the host.
+	NodePortLocal featuregate.Feature = "NodePortLocal"
+	// SecondaryNetwork enables Antrea secondary network support.
+	SecondaryNetwork featuregate.Feature = "SecondaryNetwork"
+	// ServiceExternalIP enables the Service external IP management for LoadBalancer Services.
+	ServiceExternalIP featuregate.Feature = "ServiceExternalIP"
+	// SupportBundleCollection enables SupportBundleCollection CRD for collecting support bundles from Nodes and external nodes.
+	SupportBundleCollection featuregate.Feature = "SupportBundleCollection"
+	// TopologyAwareHints enables Topology Aware Hints for AntreaProxy.
+	TopologyAwareHints featuregate.Feature = "TopologyAwareHints"
+	// Traceflow enables Antrea Traceflow.
+	Traceflow featuregate.Feature = "Traceflow"
+	// TrafficControl enables TrafficControl CRD for redirecting or mirroring traffic.
+	TrafficControl featuregate.Feature = "TrafficControl"
+)
+
+// DefaultAntreaFeatureGates consists of all known Antrea-specific feature keys and their default
+// enabled states. To add a new feature, define a key for it and add it here.
+var DefaultAntreaFeatureGates = defaultAntreaFeatureGates()
+
+func defaultAntreaFeatureGates() map[featuregate.Feature]featuregate.FeatureSpec {
+	gates := map[featuregate.Feature]featuregate.FeatureSpec{
+		AdminNetworkPolicy:          {Default: false, PreRelease: featuregate.Alpha},
+		AntreaIPAM:                  {Default: false, PreRelease: featuregate.Alpha},
+		AntreaPolicy:                {Default: true, PreRelease: featuregate.Beta},
+		AntreaProxy:                 {Default: true, PreRelease: featuregate.Beta},
+		CleanupStaleUDPSvcConntrack: {Default: false, PreRelease: featuregate.Alpha},
+		Egress:                      {Default: true, PreRelease: featuregate.Beta},
+		EndpointSlice:               {Default: true, PreRelease: featuregate.GA},
+		ExternalNode:                {Default: false, PreRelease: featuregate.Alpha},
+		FlowExporter:                {Default: false, PreRelease: featuregate.Alpha},
+		IPsecCertAuth:               {Default: false, PreRelease: featuregate.Alpha},
+		L7NetworkPolicy:             {Default: false, PreRelease: featuregate.Alpha},
+		LoadBalancerModeDSR:         {Default: false, PreRelease: featuregate.Alpha},
+		Multicast:                   {Default: true, PreRelease: featuregate.Beta},
+		Multicluster:                {Default: false, PreRelease: featuregate.Alpha},
+		NetworkPolicyStats:          {Default: true, PreRelease: featuregate.Beta},
+		NodeIPAM:                    {Default: true, PreRelease: featuregate.Beta},
+		NodePortLocal:               {Default: true, PreRelease: featuregate.Beta},
+		SecondaryNetwork:            {Default: false, PreRelease: featuregate.Alpha},
+		ServiceExternalIP:           {Default: false, PreRelease: featuregate.Alpha},
+		SupportBundleCollection:     {Default: false, PreRelease: featuregate.Alpha},
+		TopologyAwareHints:          {Default: true, PreRelease: featuregate.Beta},
+		Traceflow:                   {Default: true, PreRelease: featuregate.Beta},
+		TrafficControl:              {Default: false, PreRelease: featuregate.Alpha},
+	}
+	// Egress and Multicast rely on OVS datapath support that is not available on Windows yet.
+	if antrearuntime.IsWindowsPlatform() {
+		gates[Egress] = featuregate.FeatureSpec{Default: false, PreRelease: featuregate.Beta}
+		gates[Multicast] = featuregate.FeatureSpec{Default: false, PreRelease: featuregate.Beta}
+	}
+	return gates
+}
+
+// RuntimeMutableFeatures lists the features that are safe to toggle at runtime, e.g. through the
+// featuregates API's PATCH/PUT support, without restarting the owning component or reprogramming
+// the OVS pipeline. A feature absent from this map is treated as immutable at runtime: it can
+// only be changed by editing the antrea-config ConfigMap and restarting the component.
+var RuntimeMutableFeatures = map[featuregate.Feature]bool{
+	CleanupStaleUDPSvcConntrack: true,
+	FlowExporter:                true,
+	NetworkPolicyStats:          true,
+	SupportBundleCollection:     true,
+}
+
+// DefaultMutableFeatureGate is the mutable version of DefaultFeatureGate, which is used to
+// register all the Antrea specific feature keys.
+var DefaultMutableFeatureGate = featuregate.NewFeatureGate()
+
+func init() {
+	runtime.Must(DefaultMutableFeatureGate.Add(DefaultAntreaFeatureGates))
+}
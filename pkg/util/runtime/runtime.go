@@ -0,0 +1,26 @@
+// Copyright 2021 Antrea Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import "runtime"
+
+// WindowsOS is the name Go uses for the Windows platform in runtime.GOOS.
+const WindowsOS = "windows"
+
+// IsWindowsPlatform returns true if the Antrea component this process belongs to is
+// running on a Windows Node.
+func IsWindowsPlatform() bool {
+	return runtime.GOOS == WindowsOS
+}